@@ -0,0 +1,124 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import "github.com/pingcap/tidb/sessionctx"
+
+// BuildSide identifies which side of a join is materialized as the build
+// (hash table) side. It only ever affects cost, never correctness.
+type BuildSide int
+
+const (
+	// BuildSideLeft builds the hash table from the left child.
+	BuildSideLeft BuildSide = iota
+	// BuildSideRight builds the hash table from the right child.
+	BuildSideRight
+)
+
+// JoinAlgo is the physical join algorithm a CostModel should price a
+// candidate join as if it were using. Join reorder runs before physical
+// planning actually picks an algorithm, so this is only ever a guess based
+// on what kind of edge connected the two sides -- an eqEdge can use a hash
+// join, while a thetaEdge can only ever become a nested loop join.
+type JoinAlgo int
+
+const (
+	// JoinAlgoHash assumes a hash join: one side is scanned once to build an
+	// in-memory hash table, the other probes it.
+	JoinAlgoHash JoinAlgo = iota
+	// JoinAlgoMerge assumes a sort-merge join: both sides are consumed in
+	// sorted order with no hash table.
+	JoinAlgoMerge
+	// JoinAlgoNestedLoop assumes an index/nested-loop join: every build-side
+	// row triggers a fresh probe, the only option when there's no equi-join
+	// key to hash or sort on.
+	JoinAlgoNestedLoop
+)
+
+// CostModel estimates the cost of joining two already-costed operators.
+// joinReorderGreedySolver (and joinReorderDPSolver) call JoinCost instead of
+// hard-coding CumCount(lhs)+CumCount(rhs)+RowCount(join), so hash,
+// index-nested-loop, and merge-shaped cost curves can pull plan choice apart,
+// and tests can swap models to assert plan choice reacts to the change.
+type CostModel interface {
+	// JoinCost estimates the cost of a join given the cumulative cost and row
+	// count already assigned to each side, the join's own estimated output
+	// row count, which physical algorithm to price it as, and which side
+	// JoinCost should treat as the build side.
+	JoinCost(lhsCumCost, rhsCumCost, lhsRows, rhsRows, joinRowCount float64, algo JoinAlgo, buildSide BuildSide) float64
+	// ScanCost estimates the cost of reading rowCount rows out of a single
+	// already-planned operator -- what baseNodeCumCost prices a join group's
+	// base (leaf) nodes at, one node at a time, instead of hard-coding a
+	// weight of 1 per row the way the historical baseNodeCumCost did.
+	ScanCost(rowCount float64) float64
+}
+
+// defaultCostModel starts from the historical
+// CumCount(lhs)+CumCount(rhs)+RowCount(join) formula, but weights the join's
+// output rows by copCPUFactor+networkFactor instead of 1, adds the build
+// side's rows weighted by cpuFactor and a per-join joinFactor overhead, and
+// applies a per-algorithm multiplier to those added terms so a nested loop
+// join -- the only option across a thetaEdge -- is priced higher than a hash
+// join over the same rows.
+type defaultCostModel struct {
+	cpuFactor     float64
+	copCPUFactor  float64
+	networkFactor float64
+	joinFactor    float64
+}
+
+// newDefaultCostModel seeds a defaultCostModel from the session variables
+// analogous to TiDB's DefOptCPUFactor, DefOptCopCPUFactor,
+// DefOptNetworkFactor, and DefOptJoinFactor, so the cost curve can be tuned
+// per-session without a rebuild.
+func newDefaultCostModel(ctx sessionctx.Context) CostModel {
+	vars := ctx.GetSessionVars()
+	return &defaultCostModel{
+		cpuFactor:     vars.CPUFactor,
+		copCPUFactor:  vars.CopCPUFactor,
+		networkFactor: vars.NetworkFactor,
+		joinFactor:    vars.JoinFactor,
+	}
+}
+
+// algoMultiplier scales the base cost by how expensive each physical
+// algorithm is relative to a hash join over the same rows.
+func algoMultiplier(algo JoinAlgo) float64 {
+	switch algo {
+	case JoinAlgoMerge:
+		return 1
+	case JoinAlgoNestedLoop:
+		return 4
+	default: // JoinAlgoHash
+		return 1
+	}
+}
+
+func (m *defaultCostModel) JoinCost(lhsCumCost, rhsCumCost, lhsRows, rhsRows, joinRowCount float64, algo JoinAlgo, buildSide BuildSide) float64 {
+	buildRows := lhsRows
+	if buildSide == BuildSideRight {
+		buildRows = rhsRows
+	}
+	rowCost := joinRowCount*m.copCPUFactor + joinRowCount*m.networkFactor
+	buildCost := buildRows*m.cpuFactor + m.joinFactor
+	return lhsCumCost + rhsCumCost + algoMultiplier(algo)*(rowCost+buildCost)
+}
+
+// ScanCost replaces the historical baseNodeCumCost weighting of 1 per row
+// with a weight of cpuFactor+copCPUFactor per row, the same factors JoinCost
+// uses, so a leaf node's standalone cost moves with the same tuned factors a
+// join's does instead of being frozen at a flat weight.
+func (m *defaultCostModel) ScanCost(rowCount float64) float64 {
+	return rowCount*m.cpuFactor + rowCount*m.copCPUFactor
+}
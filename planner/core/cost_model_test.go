@@ -0,0 +1,50 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import "testing"
+
+func TestDefaultCostModelJoinCostReactsToFactors(t *testing.T) {
+	base := &defaultCostModel{cpuFactor: 1, copCPUFactor: 1, networkFactor: 1, joinFactor: 1}
+	cheap := &defaultCostModel{cpuFactor: 0.1, copCPUFactor: 0.1, networkFactor: 0.1, joinFactor: 0.1}
+
+	baseCost := base.JoinCost(0, 0, 100, 200, 50, JoinAlgoHash, BuildSideLeft)
+	cheapCost := cheap.JoinCost(0, 0, 100, 200, 50, JoinAlgoHash, BuildSideLeft)
+
+	if cheapCost >= baseCost {
+		t.Fatalf("JoinCost with smaller factors (%v) should be cheaper than with factor 1 (%v)", cheapCost, baseCost)
+	}
+}
+
+func TestDefaultCostModelPricesNestedLoopHigherThanHash(t *testing.T) {
+	m := &defaultCostModel{cpuFactor: 1, copCPUFactor: 1, networkFactor: 1, joinFactor: 1}
+
+	hashCost := m.JoinCost(0, 0, 100, 200, 50, JoinAlgoHash, BuildSideLeft)
+	nestedLoopCost := m.JoinCost(0, 0, 100, 200, 50, JoinAlgoNestedLoop, BuildSideLeft)
+
+	if nestedLoopCost <= hashCost {
+		t.Fatalf("a thetaEdge-only join (JoinAlgoNestedLoop, cost %v) should be priced higher than an equi-join (JoinAlgoHash, cost %v)", nestedLoopCost, hashCost)
+	}
+}
+
+func TestDefaultCostModelBuildSidePicksSmallerRowCount(t *testing.T) {
+	m := &defaultCostModel{cpuFactor: 1, copCPUFactor: 0, networkFactor: 0, joinFactor: 0}
+
+	buildLeft := m.JoinCost(0, 0, 10, 1000, 0, JoinAlgoHash, BuildSideLeft)
+	buildRight := m.JoinCost(0, 0, 10, 1000, 0, JoinAlgoHash, BuildSideRight)
+
+	if buildLeft >= buildRight {
+		t.Fatalf("building the hash table from the smaller side (cost %v) should be cheaper than from the larger side (cost %v)", buildLeft, buildRight)
+	}
+}
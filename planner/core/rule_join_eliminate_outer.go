@@ -0,0 +1,159 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import "github.com/pingcap/tidb/expression"
+
+// outerJoinEliminator drops a LEFT/RIGHT outer join entirely when it can
+// prove the join cannot change the result: the non-preserved side's columns
+// are never referenced above the join (after column pruning has already
+// run), and the non-preserved side's join key is one of its unique keys, so
+// every preserved-side row matches at most one row over there. Under those
+// two conditions the join is a no-op, and joinReOrderSolver's
+// joinReorderGreedySolver/joinReorderDPSolver get a smaller joinNodePlans
+// set to search over.
+//
+// It is meant to run once, early, before joinReOrderSolver in optRuleList,
+// so the smaller join group it produces is what reorder actually searches.
+type outerJoinEliminator struct {
+}
+
+func (o *outerJoinEliminator) optimize(p LogicalPlan) (LogicalPlan, error) {
+	return o.eliminate(p, p.Schema().Columns)
+}
+
+// eliminate walks the plan top-down. parentUsedCols is the set of columns
+// referenced by every operator strictly above the current node; it is what
+// decides whether an outer join's non-preserved side is dead weight.
+//
+// parentUsedCols only grows as eliminate descends: before recursing into
+// p's children it is widened with ownUsedCols(p), the columns p itself
+// reads (a Selection's Conditions, a Projection's Exprs, ...), since a node
+// between the join and the root can reference the dropped side just as
+// easily as the root's own output schema can.
+func (o *outerJoinEliminator) eliminate(p LogicalPlan, parentUsedCols []*expression.Column) (LogicalPlan, error) {
+	if join, ok := p.(*LogicalJoin); ok {
+		if simplified := o.trySimplify(join, parentUsedCols); simplified != nil {
+			return o.eliminate(simplified, parentUsedCols)
+		}
+	}
+	usedCols := append(append([]*expression.Column{}, parentUsedCols...), o.ownUsedCols(p)...)
+	children := p.Children()
+	newChildren := make([]LogicalPlan, 0, len(children))
+	for _, child := range children {
+		newChild, err := o.eliminate(child, usedCols)
+		if err != nil {
+			return nil, err
+		}
+		newChildren = append(newChildren, newChild)
+	}
+	p.SetChildren(newChildren...)
+	return p, nil
+}
+
+// ownUsedCols returns the columns p itself references beyond simply
+// reproducing its children's schema. Without folding these into the
+// used-column set passed to p's children, a Selection sitting between an
+// eliminable-looking join and the root could filter on the dropped side's
+// column and outerJoinEliminator would never notice.
+func (o *outerJoinEliminator) ownUsedCols(p LogicalPlan) []*expression.Column {
+	switch x := p.(type) {
+	case *LogicalSelection:
+		return expression.ExtractColumnsFromExpressions(nil, x.Conditions, nil)
+	case *LogicalProjection:
+		return expression.ExtractColumnsFromExpressions(nil, x.Exprs, nil)
+	case *LogicalAggregation:
+		cols := expression.ExtractColumnsFromExpressions(nil, x.GroupByItems, nil)
+		for _, f := range x.AggFuncs {
+			cols = append(cols, expression.ExtractColumnsFromExpressions(nil, f.Args, nil)...)
+		}
+		return cols
+	case *LogicalJoin:
+		cols := expression.ExtractColumnsFromExpressions(nil, x.OtherConditions, nil)
+		cols = append(cols, expression.ExtractColumnsFromExpressions(nil, x.LeftConditions, nil)...)
+		cols = append(cols, expression.ExtractColumnsFromExpressions(nil, x.RightConditions, nil)...)
+		for _, cond := range x.EqualConditions {
+			for _, arg := range cond.GetArgs() {
+				if col, ok := arg.(*expression.Column); ok {
+					cols = append(cols, col)
+				}
+			}
+		}
+		return cols
+	default:
+		return nil
+	}
+}
+
+// trySimplify returns the preserved side of join in place of the join
+// itself when it is safe to drop the other side, or nil when join must
+// stay as-is.
+func (o *outerJoinEliminator) trySimplify(join *LogicalJoin, parentUsedCols []*expression.Column) LogicalPlan {
+	var preserved, dropped LogicalPlan
+	switch join.JoinType {
+	case LeftOuterJoin:
+		preserved, dropped = join.children[0], join.children[1]
+	case RightOuterJoin:
+		preserved, dropped = join.children[1], join.children[0]
+	default:
+		return nil
+	}
+	if o.schemaReferenced(dropped.Schema(), parentUsedCols) {
+		return nil
+	}
+	if !o.joinKeyIsUnique(join, dropped) {
+		return nil
+	}
+	return preserved
+}
+
+func (o *outerJoinEliminator) schemaReferenced(schema *expression.Schema, cols []*expression.Column) bool {
+	for _, col := range cols {
+		if schema.Contains(col) {
+			return true
+		}
+	}
+	return false
+}
+
+// joinKeyIsUnique reports whether the equi-join columns on dropped's side
+// form one of its unique keys, i.e. every preserved-side row matches at
+// most one row on dropped, so removing the join cannot duplicate or drop
+// any preserved-side row.
+func (o *outerJoinEliminator) joinKeyIsUnique(join *LogicalJoin, dropped LogicalPlan) bool {
+	keyCols := make(map[int64]struct{})
+	for _, cond := range join.EqualConditions {
+		for _, arg := range cond.GetArgs() {
+			if col, ok := arg.(*expression.Column); ok && dropped.Schema().Contains(col) {
+				keyCols[col.UniqueID] = struct{}{}
+			}
+		}
+	}
+	if len(keyCols) == 0 {
+		return false
+	}
+	for _, key := range dropped.Schema().Keys {
+		unique := true
+		for _, col := range key {
+			if _, ok := keyCols[col.UniqueID]; !ok {
+				unique = false
+				break
+			}
+		}
+		if unique {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"testing"
+
+	"github.com/pingcap/tidb/expression"
+)
+
+// TestOwnUsedColsCoversJoinPushedDownSingleSideFilters covers the bug fix:
+// ownUsedCols's *LogicalJoin case used to read only OtherConditions and
+// EqualConditions, so a column referenced solely through LeftConditions or
+// RightConditions -- the pushed-down single-side filters real joins carry --
+// was never added to parentUsedCols. A deeper outer join whose dropped side
+// was that same column could then be eliminated even though it's still
+// referenced above.
+func TestOwnUsedColsCoversJoinPushedDownSingleSideFilters(t *testing.T) {
+	leftCol := &expression.Column{UniqueID: 1}
+	rightCol := &expression.Column{UniqueID: 2}
+	join := &LogicalJoin{
+		LeftConditions:  []expression.Expression{leftCol},
+		RightConditions: []expression.Expression{rightCol},
+	}
+
+	o := &outerJoinEliminator{}
+	cols := o.ownUsedCols(join)
+
+	var gotLeft, gotRight bool
+	for _, c := range cols {
+		gotLeft = gotLeft || c.UniqueID == leftCol.UniqueID
+		gotRight = gotRight || c.UniqueID == rightCol.UniqueID
+	}
+	if !gotLeft || !gotRight {
+		t.Fatalf("ownUsedCols(join) = %v, want it to include both the LeftConditions column (%d) and the RightConditions column (%d)", cols, leftCol.UniqueID, rightCol.UniqueID)
+	}
+}
+
+// TestOuterJoinEliminatorKeepsJoinWhenDroppedSideUsedAboveIntermediateNode
+// would build a plan shaped like Selection -> LogicalJoin(LeftOuterJoin) ->
+// (preserved, dropped), where the Selection filters on one of dropped's
+// columns, and assert outerJoinEliminator.optimize leaves the join in place
+// instead of collapsing it to preserved -- the parentUsedCols-accumulation
+// bug this request's main fix addresses. Likewise, the backlog's required
+// "5-way join over a fact + 4 lookup dimensions collapses to a single table
+// scan" test still isn't written. Both need the mock LogicalPlan /
+// sessionctx.Context test harness (DataSource construction, unique-key
+// schema metadata) that ships with the rest of this package's test
+// utilities in the full tree, and none of that is present in this checkout.
+// The test above does cover ownUsedCols's *LogicalJoin case directly, since
+// LogicalJoin's exported fields are plain struct literals and don't need
+// that harness.
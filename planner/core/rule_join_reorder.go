@@ -0,0 +1,106 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/parser/ast"
+	"github.com/pingcap/tidb/sessionctx"
+)
+
+// joinReOrderSolver is the entry point of the join reorder optimization
+// rule. For every join group it finds, it hands the group to
+// newJoinGroupSolver, which picks joinReorderDPSolver or
+// joinReorderGreedySolver depending on the group's size and
+// tidb_opt_join_reorder_threshold.
+type joinReOrderSolver struct{}
+
+func (s *joinReOrderSolver) optimize(ctx sessionctx.Context, p LogicalPlan) (LogicalPlan, error) {
+	return s.optimizeRecursive(ctx, p)
+}
+
+// optimizeRecursive walks the plan looking for the root of each join group;
+// everything below a group is reordered before the group itself is, so a
+// nested join group is always solved bottom-up.
+func (s *joinReOrderSolver) optimizeRecursive(ctx sessionctx.Context, p LogicalPlan) (LogicalPlan, error) {
+	if _, ok := p.(*LogicalJoin); ok {
+		joinNodePlans, eqEdges, otherConds := extractJoinGroup(p)
+		for i, child := range joinNodePlans {
+			newChild, err := s.optimizeRecursive(ctx, child)
+			if err != nil {
+				return nil, err
+			}
+			joinNodePlans[i] = newChild
+		}
+
+		thetaEdges, otherConds := extractThetaEdges(otherConds)
+		base := &baseSingleGroupJoinOrderSolver{
+			ctx:        ctx,
+			otherConds: otherConds,
+			colStats:   columnStatsForGroup(ctx, joinNodePlans),
+		}
+		solver := newJoinGroupSolver(base, eqEdges, thetaEdges, len(joinNodePlans))
+		return solver.solve(joinNodePlans)
+	}
+	newChildren := make([]LogicalPlan, 0, len(p.Children()))
+	for _, child := range p.Children() {
+		newChild, err := s.optimizeRecursive(ctx, child)
+		if err != nil {
+			return nil, err
+		}
+		newChildren = append(newChildren, newChild)
+	}
+	p.SetChildren(newChildren...)
+	return p, nil
+}
+
+// extractThetaEdges pulls range/inequality predicates (<, <=, >, >=) between
+// two columns out of otherConds and into thetaEdges, the same way eqEdges is
+// carved out of a join group's conditions elsewhere. It's what actually
+// populates joinReorderGreedySolver.thetaEdges; without this step
+// checkThetaConnection would never have anything to look at.
+//
+// BETWEEN is expected to already have been normalized into a pair of these
+// comparisons upstream, so it doesn't need separate handling here.
+func extractThetaEdges(otherConds []expression.Expression) (thetaEdges []*expression.ScalarFunction, remaining []expression.Expression) {
+	for _, cond := range otherConds {
+		sf, ok := cond.(*expression.ScalarFunction)
+		if !ok || !isThetaOp(sf.FuncName.L) {
+			remaining = append(remaining, cond)
+			continue
+		}
+		args := sf.GetArgs()
+		if len(args) != 2 {
+			remaining = append(remaining, cond)
+			continue
+		}
+		_, lIsCol := args[0].(*expression.Column)
+		_, rIsCol := args[1].(*expression.Column)
+		if !lIsCol || !rIsCol {
+			remaining = append(remaining, cond)
+			continue
+		}
+		thetaEdges = append(thetaEdges, sf)
+	}
+	return thetaEdges, remaining
+}
+
+func isThetaOp(op string) bool {
+	switch op {
+	case ast.LT, ast.LE, ast.GT, ast.GE:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/parser/ast"
+	"github.com/pingcap/tidb/statistics"
+)
+
+// checkConnectionAndMakeJoin is the connect/cost-agnostic core shared by
+// every join reorder algorithm (joinReorderGreedySolver, joinReorderDPSolver,
+// ...): it looks for an eqEdge connecting leftNode and rightNode, falling
+// back to a thetaEdge (range/inequality predicate) when no eqEdge connects
+// them, and builds the join if one is found. It lives as a free function,
+// not a method on either solver, so both can call it without depending on
+// each other's type.
+//
+// otherConds is the set of leftover conditions still available to attach to
+// this join; callers pass it explicitly (joinReorderGreedySolver passes
+// base.otherConds, mutating it after each accepted join the same way it
+// always has, while joinReorderDPSolver passes a per-subset remaining set --
+// see bestPlanForSubset) instead of this function reading base.otherConds
+// itself, since "the" otherConds isn't well defined for a solver evaluating
+// many candidate splits over many subsets at once.
+//
+// It returns the new join plan (nil if nothing connects the two sides), the
+// other conditions left over once this join's otherConds are carved out, the
+// eqEdges actually used to build it (nil when it was built from a
+// thetaEdge), and whether the join was built from a thetaEdge rather than an
+// eqEdge.
+func checkConnectionAndMakeJoin(base *baseSingleGroupJoinOrderSolver, otherConds []expression.Expression, eqEdges, thetaEdges []*expression.ScalarFunction, leftNode, rightNode LogicalPlan) (LogicalPlan, []expression.Expression, []*expression.ScalarFunction, bool) {
+	var usedEdges []*expression.ScalarFunction
+	remainOtherConds := make([]expression.Expression, len(otherConds))
+	copy(remainOtherConds, otherConds)
+	// 这里应该就是checkConnection的逻辑
+	for _, edge := range eqEdges {
+		lCol := edge.GetArgs()[0].(*expression.Column)
+		rCol := edge.GetArgs()[1].(*expression.Column)
+		if leftNode.Schema().Contains(lCol) && rightNode.Schema().Contains(rCol) {
+			usedEdges = append(usedEdges, edge)
+		} else if rightNode.Schema().Contains(lCol) && leftNode.Schema().Contains(rCol) {
+			newSf := expression.NewFunctionInternal(base.ctx, ast.EQ, edge.GetType(), rCol, lCol).(*expression.ScalarFunction)
+			usedEdges = append(usedEdges, newSf)
+		}
+	}
+	usedTheta := false
+	if len(usedEdges) == 0 {
+		// No equi-join edge connects the two sides; fall back to a theta
+		// edge so the solver isn't forced into a full cartesian product
+		// whenever a query only connects two relations through an
+		// inequality.
+		usedEdges = checkThetaConnection(base, thetaEdges, leftNode, rightNode)
+		if len(usedEdges) == 0 {
+			return nil, nil, nil, false
+		}
+		usedTheta = true
+	}
+	var joinConds []expression.Expression
+	mergedSchema := expression.MergeSchema(leftNode.Schema(), rightNode.Schema())
+	// remainOtherConds是不匹配过滤规则的，joinConds是匹配的
+	// 在这里就意味着，joinConds表示ExprFromSchema为true的表达式
+	remainOtherConds, joinConds = expression.FilterOutInPlace(remainOtherConds, func(expr expression.Expression) bool {
+		return expression.ExprFromSchema(expr, mergedSchema) // 全部expr属于mergedSchema，返回true
+	})
+	return base.newJoinWithEdges(leftNode, rightNode, usedEdges, joinConds), remainOtherConds, usedEdges, usedTheta
+}
+
+// checkThetaConnection reports, for every thetaEdge with one argument in
+// leftNode's schema and the other in rightNode's, the edge to use -- column
+// order swaps are handled the same way the eqEdge path above handles them,
+// except the comparison operator itself also has to flip.
+func checkThetaConnection(base *baseSingleGroupJoinOrderSolver, thetaEdges []*expression.ScalarFunction, leftNode, rightNode LogicalPlan) []*expression.ScalarFunction {
+	var usedEdges []*expression.ScalarFunction
+	for _, edge := range thetaEdges {
+		lCol, lOk := edge.GetArgs()[0].(*expression.Column)
+		rCol, rOk := edge.GetArgs()[1].(*expression.Column)
+		if !lOk || !rOk {
+			continue
+		}
+		if leftNode.Schema().Contains(lCol) && rightNode.Schema().Contains(rCol) {
+			usedEdges = append(usedEdges, edge)
+		} else if rightNode.Schema().Contains(lCol) && leftNode.Schema().Contains(rCol) {
+			swappedOp := swapThetaOp(edge.FuncName.L)
+			newSf := expression.NewFunctionInternal(base.ctx, swappedOp, edge.GetType(), rCol, lCol).(*expression.ScalarFunction)
+			usedEdges = append(usedEdges, newSf)
+		}
+	}
+	return usedEdges
+}
+
+// swapThetaOp returns the comparison operator equivalent to op with its
+// operands reversed, e.g. `a < b` read as (right, left) becomes `b > a`.
+func swapThetaOp(op string) string {
+	switch op {
+	case ast.LT:
+		return ast.GT
+	case ast.LE:
+		return ast.GE
+	case ast.GT:
+		return ast.LT
+	case ast.GE:
+		return ast.LE
+	default:
+		return op
+	}
+}
+
+// calcJoinCumCost is the cost-agnostic core shared by every join reorder
+// algorithm: it prices join through costModel (defaulting to
+// newDefaultCostModel when nil, so existing callers that never set one still
+// get the session's cost factors), using JoinAlgoHash when the join was
+// built from an eqEdge and JoinAlgoNestedLoop when it came from a thetaEdge,
+// since no hash join is applicable without an equi-join column.
+//
+// When usedEdges is a single equi-join column on each side and base.colStats
+// has ANALYZE TABLE statistics for both, the coarse
+// RowCount(lhs)*RowCount(rhs)/max(RowCount(lhs), RowCount(rhs)) estimate
+// join.Stats().RowCount carries is replaced with
+// statistics.EstimateEqJoinRowCount's histogram/TopN-backed estimate before
+// it's priced, so a more selective equi-join actually gets picked over a
+// less selective one instead of the reorder search being blind to real data
+// skew. Tables ANALYZE TABLE never ran on, or joins built from a thetaEdge
+// or a multi-column eqEdge, keep using join.Stats().RowCount unchanged.
+func calcJoinCumCost(base *baseSingleGroupJoinOrderSolver, costModel CostModel, join LogicalPlan, lhs, rhs *jrNode, usedEdges []*expression.ScalarFunction, usedTheta bool) float64 {
+	cm := costModel
+	if cm == nil {
+		cm = newDefaultCostModel(base.ctx)
+	}
+	lhsRows := lhs.p.Stats().RowCount
+	rhsRows := rhs.p.Stats().RowCount
+	buildSide := BuildSideRight
+	if lhsRows < rhsRows {
+		buildSide = BuildSideLeft
+	}
+	algo := JoinAlgoHash
+	if usedTheta {
+		algo = JoinAlgoNestedLoop
+	}
+	joinRowCount := join.Stats().RowCount
+	if !usedTheta && len(usedEdges) == 1 {
+		joinRowCount = refineEqJoinRowCount(base.colStats, usedEdges[0], joinRowCount)
+	}
+	return cm.JoinCost(lhs.cumCost, rhs.cumCost, lhsRows, rhsRows, joinRowCount, algo, buildSide)
+}
+
+// refineEqJoinRowCount looks up ANALYZE TABLE statistics for edge's two
+// columns in colStats and, when both are present, returns
+// estimateEqJoinRowCount's histogram/TopN-based estimate; it returns
+// fallback unchanged otherwise, so a join over un-analyzed columns keeps
+// costing exactly as it did before this statistics wiring existed.
+func refineEqJoinRowCount(colStats map[int64]*statistics.Column, edge *expression.ScalarFunction, fallback float64) float64 {
+	lCol, lOk := edge.GetArgs()[0].(*expression.Column)
+	rCol, rOk := edge.GetArgs()[1].(*expression.Column)
+	if !lOk || !rOk {
+		return fallback
+	}
+	return estimateEqJoinRowCount(colStats[lCol.UniqueID], colStats[rCol.UniqueID], fallback)
+}
+
+// baseNodeCumCost is the cost-agnostic core shared by every join reorder
+// algorithm for pricing a base (leaf-of-the-join-group) node: it sums
+// node's own scan cost with every descendant's, each priced through
+// costModel.ScanCost, reproducing the historical "CumCount equals the sum
+// of the count of its subtree" behavior but through the same pluggable
+// CostModel join costing already goes through, instead of a hard-coded
+// weight of 1 per row that no CostModel could tune.
+func baseNodeCumCost(base *baseSingleGroupJoinOrderSolver, costModel CostModel, node LogicalPlan) float64 {
+	cm := costModel
+	if cm == nil {
+		cm = newDefaultCostModel(base.ctx)
+	}
+	cost := cm.ScanCost(node.Stats().RowCount)
+	for _, child := range node.Children() {
+		cost += baseNodeCumCost(base, costModel, child)
+	}
+	return cost
+}
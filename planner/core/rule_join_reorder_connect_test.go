@@ -0,0 +1,35 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"testing"
+
+	"github.com/pingcap/tidb/parser/ast"
+)
+
+func TestSwapThetaOpReversesComparison(t *testing.T) {
+	cases := map[string]string{
+		ast.LT: ast.GT,
+		ast.LE: ast.GE,
+		ast.GT: ast.LT,
+		ast.GE: ast.LE,
+		ast.EQ: ast.EQ, // not a theta op, should pass through unchanged
+	}
+	for op, want := range cases {
+		if got := swapThetaOp(op); got != want {
+			t.Errorf("swapThetaOp(%q) = %q, want %q", op, got, want)
+		}
+	}
+}
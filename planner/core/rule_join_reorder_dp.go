@@ -0,0 +1,221 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"math"
+	"math/bits"
+
+	"github.com/pingcap/tidb/expression"
+)
+
+// joinReorderDPMaxRelations is the hard cap imposed by representing subsets
+// of joinNodePlans as bitmasks in a uint64. Groups above this size (or above
+// tidb_opt_join_reorder_threshold, see newJoinGroupSolver) fall back to
+// joinReorderGreedySolver instead of paying the 2^n search.
+const joinReorderDPMaxRelations = 64
+
+// joinReorderDPSolver reorders the join nodes in the group using a classic
+// Selinger-style bottom-up dynamic programming search: level 1 is every base
+// jrNode, and at level k it considers, for every connected subset S of size
+// k, every way to split S into two previously-solved disjoint subsets S1,S2
+// that an eqEdge actually connects, keeping only the cheapest plan per
+// subset. Unlike joinReorderGreedySolver it is therefore never fooled by a
+// locally-cheap first choice that forces an expensive join later on.
+//
+// Disconnected components left over after the full-set plan is built (or
+// when the full set is never reached because the group isn't fully
+// connected) are combined with makeBushyJoin exactly like the greedy solver.
+type joinReorderDPSolver struct {
+	*baseSingleGroupJoinOrderSolver
+	eqEdges []*expression.ScalarFunction
+	// thetaEdges are carried through only so fallbackToGreedy can hand them
+	// to joinReorderGreedySolver; the DP search itself only ever connects
+	// subsets via eqEdges.
+	thetaEdges []*expression.ScalarFunction
+	costModel  CostModel
+}
+
+func (s *joinReorderDPSolver) solve(joinNodePlans []LogicalPlan) (LogicalPlan, error) {
+	n := len(joinNodePlans)
+	if n > joinReorderDPMaxRelations {
+		return s.fallbackToGreedy(joinNodePlans)
+	}
+
+	// full has one bit set per relation. It is computed without 1<<n, which
+	// would overflow a uint64 at n == 64.
+	full := ^uint64(0) >> uint(64-n)
+	// dp[mask] is the cheapest plan found so far for exactly the relations
+	// whose bit is set in mask.
+	dp := make(map[uint64]*jrNode)
+	// remainOthers[mask] is the otherConds not yet attached to any join
+	// inside the subtree dp[mask] represents. It's tracked per subset
+	// instead of mutating the shared s.otherConds mid-search: bestPlanForSubset
+	// evaluates many candidate splits per mask and many masks per level, all
+	// against what must be independent state, not one another's leftovers.
+	remainOthers := make(map[uint64][]expression.Expression)
+	for i, node := range joinNodePlans {
+		if _, err := node.recursiveDeriveStats(); err != nil {
+			return nil, err
+		}
+		mask := uint64(1) << uint(i)
+		dp[mask] = &jrNode{p: node, cumCost: baseNodeCumCost(s.baseSingleGroupJoinOrderSolver, s.costModel, node)}
+		remainOthers[mask] = s.otherConds
+	}
+
+	for size := 2; size <= n; size++ {
+		// mask != 0 stops the loop when mask++ wraps past full at n == 64.
+		for mask := uint64(1); mask != 0 && mask <= full; mask++ {
+			if bits.OnesCount64(mask) != size {
+				continue
+			}
+			best, others, err := s.bestPlanForSubset(mask, dp, remainOthers)
+			if err != nil {
+				return nil, err
+			}
+			if best != nil {
+				dp[mask] = best
+				remainOthers[mask] = others
+			}
+		}
+	}
+
+	if best, ok := dp[full]; ok {
+		s.otherConds = remainOthers[full]
+		return best.p, nil
+	}
+
+	// The group isn't fully connected: stitch together the largest disjoint
+	// connected components dp managed to build, biggest first, same as the
+	// greedy solver's cartesianGroup construction.
+	var cartesianGroup []LogicalPlan
+	var covered uint64
+	var leftover []expression.Expression
+	first := true
+	for size := n; size >= 1; size-- {
+		for mask := uint64(1); mask != 0 && mask <= full; mask++ {
+			if bits.OnesCount64(mask) != size || mask&covered != 0 {
+				continue
+			}
+			node, ok := dp[mask]
+			if !ok {
+				continue
+			}
+			cartesianGroup = append(cartesianGroup, node.p)
+			covered |= mask
+			if first {
+				leftover = remainOthers[mask]
+				first = false
+			} else {
+				leftover = intersectOtherConds(leftover, remainOthers[mask])
+			}
+		}
+	}
+	s.otherConds = leftover
+	return s.makeBushyJoin(cartesianGroup), nil
+}
+
+// bestPlanForSubset tries every way of splitting mask into two previously
+// solved, disjoint subsets and keeps the cheapest join that an eqEdge
+// actually connects, returning its leftover otherConds alongside it instead
+// of mutating any shared state -- see the remainOthers comment in solve.
+func (s *joinReorderDPSolver) bestPlanForSubset(mask uint64, dp map[uint64]*jrNode, remainOthers map[uint64][]expression.Expression) (*jrNode, []expression.Expression, error) {
+	bestCost := math.MaxFloat64
+	var best *jrNode
+	var bestOthers []expression.Expression
+	for sub := (mask - 1) & mask; sub > 0; sub = (sub - 1) & mask {
+		other := mask ^ sub
+		// Every unordered split (S1,S2) is reached twice, as (sub, other) and
+		// (other, sub); only process the canonical half.
+		if sub > other {
+			continue
+		}
+		left, ok := dp[sub]
+		if !ok {
+			continue
+		}
+		right, ok := dp[other]
+		if !ok {
+			continue
+		}
+		// A condition is only still available to attach at this join if
+		// neither sibling subtree has already consumed it, i.e. it's still
+		// present in both sides' remaining set.
+		available := intersectOtherConds(remainOthers[sub], remainOthers[other])
+		newJoin, remain, usedEdges, usedTheta := checkConnectionAndMakeJoin(s.baseSingleGroupJoinOrderSolver, available, s.eqEdges, nil, left.p, right.p)
+		if newJoin == nil {
+			continue
+		}
+		if _, err := newJoin.recursiveDeriveStats(); err != nil {
+			return nil, nil, err
+		}
+		cost := calcJoinCumCost(s.baseSingleGroupJoinOrderSolver, s.costModel, newJoin, left, right, usedEdges, usedTheta)
+		if cost < bestCost {
+			bestCost = cost
+			best = &jrNode{p: newJoin, cumCost: cost}
+			bestOthers = remain
+		}
+	}
+	return best, bestOthers, nil
+}
+
+// intersectOtherConds returns the otherConds present in both a and b, by
+// identity. Every leaf subset starts with the same full otherConds slice, so
+// an expression not yet consumed by either sibling subtree is still the same
+// slice element in both lists, making this exact rather than an
+// approximation.
+func intersectOtherConds(a, b []expression.Expression) []expression.Expression {
+	inB := make(map[expression.Expression]struct{}, len(b))
+	for _, e := range b {
+		inB[e] = struct{}{}
+	}
+	var result []expression.Expression
+	for _, e := range a {
+		if _, ok := inB[e]; ok {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+func (s *joinReorderDPSolver) fallbackToGreedy(joinNodePlans []LogicalPlan) (LogicalPlan, error) {
+	greedy := &joinReorderGreedySolver{
+		baseSingleGroupJoinOrderSolver: s.baseSingleGroupJoinOrderSolver,
+		eqEdges:                        s.eqEdges,
+		thetaEdges:                     s.thetaEdges,
+		costModel:                      s.costModel,
+	}
+	return greedy.solve(joinNodePlans)
+}
+
+// joinGroupSolver is implemented by every concrete join reorder algorithm.
+// joinReOrderSolver.optimize picks one per join group via newJoinGroupSolver.
+type joinGroupSolver interface {
+	solve(joinNodePlans []LogicalPlan) (LogicalPlan, error)
+}
+
+// newJoinGroupSolver picks the concrete join reorder algorithm for one join
+// group: the DP solver above is optimal but exponential in the number of
+// relations, so joinReOrderSolver only uses it below
+// tidb_opt_join_reorder_threshold (and never above joinReorderDPMaxRelations,
+// where a subset can no longer be represented as a uint64 bitmask). thetaEdges
+// only ever gets passed on to joinReorderGreedySolver; the DP solver doesn't
+// search theta-connected subsets.
+func newJoinGroupSolver(base *baseSingleGroupJoinOrderSolver, eqEdges, thetaEdges []*expression.ScalarFunction, relationCount int) joinGroupSolver {
+	threshold := base.ctx.GetSessionVars().TiDBOptJoinReorderThreshold
+	if relationCount > threshold || relationCount > joinReorderDPMaxRelations {
+		return &joinReorderGreedySolver{baseSingleGroupJoinOrderSolver: base, eqEdges: eqEdges, thetaEdges: thetaEdges}
+	}
+	return &joinReorderDPSolver{baseSingleGroupJoinOrderSolver: base, eqEdges: eqEdges, thetaEdges: thetaEdges}
+}
@@ -0,0 +1,60 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"testing"
+
+	"github.com/pingcap/tidb/expression"
+)
+
+// TestIntersectOtherCondsOnlyKeepsConditionsStillUnconsumedByBothSiblings
+// covers the exact bug bestPlanForSubset used to have: mutating the shared
+// s.otherConds field meant one candidate split's leftover conditions could
+// silently overwrite or pollute an unrelated split's view of what's still
+// available. intersectOtherConds is what bestPlanForSubset now uses instead
+// to compute, for a (sub, other) split, the conditions neither sibling
+// subtree has already attached to a join of its own.
+func TestIntersectOtherCondsOnlyKeepsConditionsStillUnconsumedByBothSiblings(t *testing.T) {
+	shared, leftOnly, rightOnly := &expression.Column{UniqueID: 1}, &expression.Column{UniqueID: 2}, &expression.Column{UniqueID: 3}
+	left := []expression.Expression{shared, leftOnly}
+	right := []expression.Expression{shared, rightOnly}
+
+	got := intersectOtherConds(left, right)
+	if len(got) != 1 || got[0] != shared {
+		t.Fatalf("intersectOtherConds(%v, %v) = %v, want only the condition present on both sides", left, right, got)
+	}
+}
+
+func TestIntersectOtherCondsOfDisjointSetsIsEmpty(t *testing.T) {
+	left := []expression.Expression{&expression.Column{UniqueID: 1}}
+	right := []expression.Expression{&expression.Column{UniqueID: 2}}
+
+	if got := intersectOtherConds(left, right); len(got) != 0 {
+		t.Fatalf("intersectOtherConds of disjoint sets = %v, want empty", got)
+	}
+}
+
+// TestJoinReorderDPBeatsGreedyOnChainAndStarShapes would build a chain join
+// (a-b-c-d, b-c cheap but a-b/c-d expensive) and a star join (one fact table
+// joined to several small dimensions) and assert joinReorderDPSolver picks
+// the lower-cost plan where joinReorderGreedySolver's local choice is
+// provably suboptimal, per the original backlog request. It still isn't
+// written: exercising solve() end-to-end needs the mock LogicalPlan /
+// DataSource / sessionctx.Context test harness (column/schema construction,
+// a fake statistics-backed Stats()) that ships with the rest of this
+// package's test utilities in the full tree, and none of that is present in
+// this checkout. The two tests above do cover the otherConds-threading bug
+// fix in bestPlanForSubset, which is the part of this request's logic that's
+// actually reachable without that harness.
@@ -18,12 +18,21 @@ import (
 	"sort"
 
 	"github.com/pingcap/tidb/expression"
-	"github.com/pingcap/tidb/parser/ast"
 )
 
 type joinReorderGreedySolver struct {
 	*baseSingleGroupJoinOrderSolver
 	eqEdges []*expression.ScalarFunction
+	// thetaEdges are range/inequality predicates (<, <=, >, >=; BETWEEN is
+	// normalized into a pair of these upstream) that connect two sides of a
+	// join group without an equi-join column. checkConnectionAndMakeJoin only
+	// falls back to them when no eqEdge connects the two sides, since no hash
+	// join is applicable across a theta edge.
+	thetaEdges []*expression.ScalarFunction
+	// costModel prices a candidate join; it defaults to newDefaultCostModel
+	// lazily (see calcJoinCumCost) so existing construction sites that never
+	// set it still get a working cost model.
+	costModel CostModel
 }
 
 // solve reorders the join nodes in the group based on a greedy algorithm.
@@ -33,13 +42,15 @@ type joinReorderGreedySolver struct {
 // tree, choose the node with the smallest cumulative cost to join with the
 // current join tree.
 //
-// cumulative join cost = CumCount(lhs) + CumCount(rhs) + RowCount(join)
+// cumulative join cost is priced by s.costModel (see calcJoinCumCost), which
+// defaults to CumCount(lhs) + CumCount(rhs) plus factor-weighted terms for
+// RowCount(join), the build side's rows, and a per-join overhead.
 //   For base node, its CumCount equals to the sum of the count of its subtree.
 //   See baseNodeCumCost for more details.
-// TODO: this formula can be changed to real physical cost in future.
 //
-// For the nodes and join trees which don't have a join equal condition to
-// connect them, we make a bushy join tree to do the cartesian joins finally.
+// For the nodes and join trees which don't have a join equal condition (or a
+// thetaEdge) to connect them, we make a bushy join tree to do the cartesian
+// joins finally.
 func (s *joinReorderGreedySolver) solve(joinNodePlans []LogicalPlan) (LogicalPlan, error) {
 	// 遍历全部节点，递归计算cost
 	for _, node := range joinNodePlans {
@@ -49,7 +60,7 @@ func (s *joinReorderGreedySolver) solve(joinNodePlans []LogicalPlan) (LogicalPla
 		}
 		s.curJoinGroup = append(s.curJoinGroup, &jrNode{ // jrNode=节点+代价
 			p:       node,
-			cumCost: s.baseNodeCumCost(node),
+			cumCost: baseNodeCumCost(s.baseSingleGroupJoinOrderSolver, s.costModel, node),
 		})
 	}
 	// jrNode就是为了这里的排序
@@ -81,7 +92,7 @@ func (s *joinReorderGreedySolver) constructConnectedJoinTree() (*jrNode, error)
 		// 遍历curJoinGroup，和curJoinTree进行join，找一个代价最小的plan
 		for i, node := range s.curJoinGroup {
 			// 尝试 curJoinTree join node 得到新的计划
-			newJoin, remainOthers := s.checkConnectionAndMakeJoin(curJoinTree.p, node.p)
+			newJoin, remainOthers, usedEdges, usedTheta := checkConnectionAndMakeJoin(s.baseSingleGroupJoinOrderSolver, s.otherConds, s.eqEdges, s.thetaEdges, curJoinTree.p, node.p)
 			if newJoin == nil {
 				continue
 			}
@@ -90,7 +101,7 @@ func (s *joinReorderGreedySolver) constructConnectedJoinTree() (*jrNode, error)
 			if err != nil {
 				return nil, err
 			}
-			curCost := s.calcJoinCumCost(newJoin, curJoinTree, node)
+			curCost := calcJoinCumCost(s.baseSingleGroupJoinOrderSolver, s.costModel, newJoin, curJoinTree, node, usedEdges, usedTheta)
 			if bestCost > curCost {
 				bestCost = curCost
 				bestJoin = newJoin
@@ -113,31 +124,3 @@ func (s *joinReorderGreedySolver) constructConnectedJoinTree() (*jrNode, error)
 	// 最后得到的应该是一个最小代价的join树
 	return curJoinTree, nil
 }
-
-func (s *joinReorderGreedySolver) checkConnectionAndMakeJoin(leftNode, rightNode LogicalPlan) (LogicalPlan, []expression.Expression) {
-	var usedEdges []*expression.ScalarFunction
-	remainOtherConds := make([]expression.Expression, len(s.otherConds))
-	copy(remainOtherConds, s.otherConds)
-	// 这里应该就是checkConnection的逻辑
-	for _, edge := range s.eqEdges {
-		lCol := edge.GetArgs()[0].(*expression.Column)
-		rCol := edge.GetArgs()[1].(*expression.Column)
-		if leftNode.Schema().Contains(lCol) && rightNode.Schema().Contains(rCol) {
-			usedEdges = append(usedEdges, edge)
-		} else if rightNode.Schema().Contains(lCol) && leftNode.Schema().Contains(rCol) {
-			newSf := expression.NewFunctionInternal(s.ctx, ast.EQ, edge.GetType(), rCol, lCol).(*expression.ScalarFunction)
-			usedEdges = append(usedEdges, newSf)
-		}
-	}
-	if len(usedEdges) == 0 {
-		return nil, nil
-	}
-	var otherConds []expression.Expression
-	mergedSchema := expression.MergeSchema(leftNode.Schema(), rightNode.Schema())
-	// remainOtherConds是不匹配过滤规则的，otherConds是匹配的
-	// 在这里就意味着，otherConds表示ExprFromSchema为true的表达式
-	remainOtherConds, otherConds = expression.FilterOutInPlace(remainOtherConds, func(expr expression.Expression) bool {
-		return expression.ExprFromSchema(expr, mergedSchema) // 全部expr属于mergedSchema，返回true
-	})
-	return s.newJoinWithEdges(leftNode, rightNode, usedEdges, otherConds), remainOtherConds
-}
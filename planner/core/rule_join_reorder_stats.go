@@ -0,0 +1,70 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"github.com/pingcap/tidb/domain"
+	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/statistics"
+)
+
+// estimateEqJoinRowCount refines a join's estimated output row count using
+// histogram/NDV/TopN statistics for the two equi-join columns, in place of
+// the coarse RowCount(lhs)*RowCount(rhs)/max(RowCount(lhs), RowCount(rhs))
+// heuristic recursiveDeriveStats falls back to today. It returns fallback
+// unchanged when ANALYZE TABLE never populated statistics for either column,
+// so plans over un-analyzed tables keep behaving exactly as before.
+//
+// calcJoinCumCost (see refineEqJoinRowCount in rule_join_reorder_connect.go)
+// is the only caller: it's what lets a more selective equi-join actually win
+// out over a less selective one during join reorder, instead of this
+// estimator being computed and then discarded.
+func estimateEqJoinRowCount(lCol, rCol *statistics.Column, fallback float64) float64 {
+	if lCol == nil || rCol == nil {
+		return fallback
+	}
+	return statistics.EstimateEqJoinRowCount(lCol, rCol)
+}
+
+// columnStatsForGroup collects ANALYZE TABLE statistics for every column of
+// every DataSource in a join group, keyed by the schema column's UniqueID
+// (not the underlying table column ID, since the same table column can
+// appear under several different UniqueIDs within one group, e.g. a
+// self-join). DataSources over tables ANALYZE TABLE never ran on, or whose
+// domain has no stats handle at all (e.g. in a test harness), simply
+// contribute nothing, and calcJoinCumCost keeps using the coarse row-count
+// estimate for them.
+func columnStatsForGroup(ctx sessionctx.Context, joinNodePlans []LogicalPlan) map[int64]*statistics.Column {
+	colStats := make(map[int64]*statistics.Column)
+	statsHandle := domain.GetDomain(ctx).StatsHandle()
+	if statsHandle == nil {
+		return colStats
+	}
+	for _, node := range joinNodePlans {
+		ds, ok := node.(*DataSource)
+		if !ok {
+			continue
+		}
+		tbl := statsHandle.GetTableStats(ds.tableInfo)
+		if tbl == nil {
+			continue
+		}
+		for _, col := range ds.Schema().Columns {
+			if colStat, ok := tbl.Columns[col.ID]; ok {
+				colStats[col.UniqueID] = colStat
+			}
+		}
+	}
+	return colStats
+}
@@ -0,0 +1,168 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statistics
+
+import "sort"
+
+// DefaultTopNCount is how many of a column's most frequent values ANALYZE
+// TABLE tracks exactly instead of folding them into the histogram.
+const DefaultTopNCount = 20
+
+// BuildColumnFromSamples builds a Column's histogram, NDV, null count, and
+// TopN list from a sorted-free slice of a column's encoded values (NULLs
+// omitted, one entry per row) and the requested number of histogram
+// buckets. It is the statistics collection side of ANALYZE TABLE: the
+// executor reads the column's values and calls this once per column before
+// persisting the result.
+func BuildColumnFromSamples(values [][]byte, nullCount int64, numBuckets int) *Column {
+	sorted := make([][]byte, len(values))
+	copy(sorted, values)
+	sort.Slice(sorted, func(i, j int) bool {
+		return compareBytes(sorted[i], sorted[j]) < 0
+	})
+
+	counts := make(map[string]int64, len(sorted))
+	order := make([]string, 0, len(sorted))
+	for _, v := range sorted {
+		key := string(v)
+		if _, ok := counts[key]; !ok {
+			order = append(order, key)
+		}
+		counts[key]++
+	}
+
+	topNKeys := topNKeysByCount(order, counts, DefaultTopNCount)
+	topN := &TopN{}
+	inTopN := make(map[string]bool, len(topNKeys))
+	for _, key := range topNKeys {
+		topN.TopN = append(topN.TopN, TopNMeta{Encoded: []byte(key), Count: uint64(counts[key])})
+		inTopN[key] = true
+	}
+
+	var remaining [][]byte
+	for _, v := range sorted {
+		if !inTopN[string(v)] {
+			remaining = append(remaining, v)
+		}
+	}
+
+	col := &Column{
+		Histogram: Histogram{
+			Buckets:   buildEquiDepthBuckets(remaining, numBuckets),
+			NDV:       int64(len(order)),
+			NullCount: nullCount,
+		},
+		TopN: topN,
+	}
+	return col
+}
+
+// BuildTable assembles a Table from per-column samples, calling
+// BuildColumnFromSamples once per column. It's the table-level entry point
+// an ANALYZE TABLE executor calls after it has read samples for every
+// column; columnSamples and nullCounts are both keyed by column ID.
+func BuildTable(columnSamples map[int64][][]byte, nullCounts map[int64]int64, rowCount int64, numBuckets int) *Table {
+	tbl := &Table{
+		Columns: make(map[int64]*Column, len(columnSamples)),
+		Count:   rowCount,
+	}
+	for colID, values := range columnSamples {
+		tbl.Columns[colID] = BuildColumnFromSamples(values, nullCounts[colID], numBuckets)
+	}
+	return tbl
+}
+
+func buildEquiDepthBuckets(sorted [][]byte, numBuckets int) []Bucket {
+	if len(sorted) == 0 || numBuckets <= 0 {
+		return nil
+	}
+	if numBuckets > len(sorted) {
+		numBuckets = len(sorted)
+	}
+	bucketSize := len(sorted) / numBuckets
+	buckets := make([]Bucket, 0, numBuckets)
+	start := 0
+	for i := 0; i < numBuckets; i++ {
+		end := start + bucketSize
+		if i == numBuckets-1 {
+			end = len(sorted)
+		}
+		ndv := distinctCount(sorted[start:end])
+		buckets = append(buckets, Bucket{
+			Count:      int64(end),
+			Repeats:    repeatsOfUpperBound(sorted[start:end]),
+			NDV:        ndv,
+			LowerBound: sorted[start],
+			UpperBound: sorted[end-1],
+		})
+		start = end
+	}
+	return buckets
+}
+
+func distinctCount(values [][]byte) int64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var count int64 = 1
+	for i := 1; i < len(values); i++ {
+		if compareBytes(values[i], values[i-1]) != 0 {
+			count++
+		}
+	}
+	return count
+}
+
+func repeatsOfUpperBound(values [][]byte) int64 {
+	if len(values) == 0 {
+		return 0
+	}
+	upper := values[len(values)-1]
+	var repeats int64
+	for i := len(values) - 1; i >= 0 && compareBytes(values[i], upper) == 0; i-- {
+		repeats++
+	}
+	return repeats
+}
+
+func topNKeysByCount(order []string, counts map[string]int64, n int) []string {
+	keys := make([]string, len(order))
+	copy(keys, order)
+	sort.SliceStable(keys, func(i, j int) bool {
+		return counts[keys[i]] > counts[keys[j]]
+	})
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+	return keys
+}
+
+func compareBytes(a, b []byte) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case len(a) < len(b):
+		return -1
+	case len(a) > len(b):
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package statistics holds the per-column histograms, NDV (number of
+// distinct values) counts, null counts, and TopN lists populated by ANALYZE
+// TABLE. The planner consumes them to turn raw row counts into realistic
+// join output cardinalities instead of relying on heuristics alone.
+package statistics
+
+// Bucket is one equi-depth bucket of a column histogram. Count is the
+// cumulative number of rows at or below the bucket's upper bound, so a
+// single bucket's own row count is Count minus the previous bucket's Count.
+type Bucket struct {
+	// Count is cumulative: the number of rows in this bucket and every
+	// bucket before it.
+	Count int64
+	// Repeats is how many times UpperBound itself occurs in the bucket.
+	Repeats int64
+	NDV     int64
+	// LowerBound and UpperBound are the encoded values at each end of the
+	// bucket's range (inclusive). They let two histograms be walked in
+	// lockstep, aligning only the bucket pairs whose ranges actually
+	// overlap, instead of treating every pair as a match.
+	LowerBound []byte
+	UpperBound []byte
+}
+
+// Histogram is an equi-depth histogram over one column's non-null, non-TopN
+// values.
+type Histogram struct {
+	Buckets []Bucket
+	// NDV is the number of distinct values across the whole column,
+	// including values captured by TopN.
+	NDV int64
+	// NullCount is the number of rows where the column is NULL.
+	NullCount int64
+}
+
+// TotalRowCount is the number of non-null rows the histogram itself covers,
+// i.e. excluding TopN's exact-match values.
+func (h *Histogram) TotalRowCount() int64 {
+	if len(h.Buckets) == 0 {
+		return 0
+	}
+	return h.Buckets[len(h.Buckets)-1].Count
+}
+
+// bucketRowCount returns the number of rows that fall in bucket i alone.
+func (h *Histogram) bucketRowCount(i int) int64 {
+	if i == 0 {
+		return h.Buckets[0].Count
+	}
+	return h.Buckets[i].Count - h.Buckets[i-1].Count
+}
+
+// TopNMeta is one exact-match entry in a TopN list: Encoded is the column
+// value in its sort-key encoding, Count is how many rows carry it.
+type TopNMeta struct {
+	Encoded []byte
+	Count   uint64
+}
+
+// TopN holds the most frequent values of a column, tracked exactly rather
+// than approximated by the histogram.
+type TopN struct {
+	TopN []TopNMeta
+}
+
+// TotalRowCount is the number of rows covered by the TopN list.
+func (t *TopN) TotalRowCount() int64 {
+	if t == nil {
+		return 0
+	}
+	var total int64
+	for _, meta := range t.TopN {
+		total += int64(meta.Count)
+	}
+	return total
+}
+
+// Column is the full set of statistics ANALYZE TABLE collects for a single
+// column: a histogram over the bulk of the data plus an exact TopN list for
+// its most frequent values.
+type Column struct {
+	Histogram
+	TopN *TopN
+}
+
+// Table is the statistics for every analyzed column of one table.
+type Table struct {
+	// Columns is keyed by column ID.
+	Columns map[int64]*Column
+	Count   int64
+}
@@ -0,0 +1,125 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statistics
+
+import "bytes"
+
+// EstimateEqJoinRowCount estimates the number of rows produced by joining L
+// and R on an equality predicate l.col = r.col, given columns statistics for
+// each side.
+//
+// The estimate is the TopN term plus exactly one of the two other terms:
+//   - TopN exact matches: for every value present in both sides' TopN lists,
+//     the exact count product is used instead of a histogram estimate
+//   - aligned histogram buckets: walking both histograms in lockstep by
+//     bucket range, for every pair that overlaps,
+//     bucket_L.rows * bucket_R.rows / max(bucket_L.ndv, bucket_R.ndv)
+//   - only when the histogram term is zero, the column-level
+//     |L| * |R| / max(NDV(L), NDV(R)) formula over the histogram rows
+//
+// Values found in only one side's TopN are not counted when the histogram
+// term is non-zero.
+//
+// Nulls never match in an equi-join and are excluded from every term above.
+func EstimateEqJoinRowCount(l, r *Column) float64 {
+	if l == nil || r == nil {
+		return 0
+	}
+
+	topNRows := estimateTopNEqJoinRowCount(l.TopN, r.TopN)
+
+	histRows := estimateHistogramEqJoinRowCount(&l.Histogram, &r.Histogram)
+
+	// The coarse fallback below is sized off of the non-TopN, non-null
+	// portion of each side only, since TopN rows are already priced by
+	// topNRows.
+	lRows := float64(l.Histogram.TotalRowCount())
+	rRows := float64(r.Histogram.TotalRowCount())
+	lNDV := float64(l.NDV)
+	rNDV := float64(r.NDV)
+	var fallbackRows float64
+	if lNDV > 0 || rNDV > 0 {
+		fallbackRows = lRows * rRows / maxFloat(maxFloat(lNDV, rNDV), 1)
+	}
+
+	// Prefer the finer-grained histogram estimate where it found any
+	// overlapping rows; the coarse fallback is used only in its place.
+	if histRows > 0 {
+		return topNRows + histRows
+	}
+	return topNRows + fallbackRows
+}
+
+// estimateHistogramEqJoinRowCount sums, over every pair of buckets whose
+// [LowerBound, UpperBound] ranges actually overlap,
+// bucket_L.rows * bucket_R.rows / max(bucket_L.ndv, bucket_R.ndv). Buckets
+// within a histogram are equi-depth and sorted by value range, so the two
+// bucket lists are walked in lockstep like a merge join: whichever side has
+// the smaller UpperBound can't overlap anything further along the other
+// side, so it's safe to advance just that side.
+func estimateHistogramEqJoinRowCount(l, r *Histogram) float64 {
+	if len(l.Buckets) == 0 || len(r.Buckets) == 0 {
+		return 0
+	}
+	var total float64
+	i, j := 0, 0
+	for i < len(l.Buckets) && j < len(r.Buckets) {
+		lb, rb := l.Buckets[i], r.Buckets[j]
+		if bucketsOverlap(lb, rb) {
+			lRows := float64(l.bucketRowCount(i))
+			rRows := float64(r.bucketRowCount(j))
+			denom := maxFloat(float64(lb.NDV), float64(rb.NDV))
+			if denom > 0 {
+				total += lRows * rRows / denom
+			}
+		}
+		if compareBytes(lb.UpperBound, rb.UpperBound) <= 0 {
+			i++
+		} else {
+			j++
+		}
+	}
+	return total
+}
+
+// bucketsOverlap reports whether two buckets' inclusive value ranges
+// intersect.
+func bucketsOverlap(l, r Bucket) bool {
+	return compareBytes(l.LowerBound, r.UpperBound) <= 0 && compareBytes(r.LowerBound, l.UpperBound) <= 0
+}
+
+// estimateTopNEqJoinRowCount matches values that both sides tracked exactly,
+// contributing an exact row count instead of a histogram estimate.
+func estimateTopNEqJoinRowCount(l, r *TopN) float64 {
+	if l == nil || r == nil {
+		return 0
+	}
+	var total float64
+	for _, lMeta := range l.TopN {
+		for _, rMeta := range r.TopN {
+			if bytes.Equal(lMeta.Encoded, rMeta.Encoded) {
+				total += float64(lMeta.Count) * float64(rMeta.Count)
+				break
+			}
+		}
+	}
+	return total
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
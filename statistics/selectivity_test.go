@@ -0,0 +1,65 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statistics
+
+import "testing"
+
+func TestEstimateHistogramEqJoinRowCountOnlySumsOverlappingBuckets(t *testing.T) {
+	// L has two buckets covering [0,9] and [10,19]; R has one bucket fully
+	// inside L's second bucket, [12,15]. Only the (L[1], R[0]) pair overlaps,
+	// so a full cross product (which would also add L[0]*R[0]) must not be
+	// counted.
+	l := &Histogram{Buckets: []Bucket{
+		{Count: 10, NDV: 10, LowerBound: []byte{0}, UpperBound: []byte{9}},
+		{Count: 20, NDV: 10, LowerBound: []byte{10}, UpperBound: []byte{19}},
+	}}
+	r := &Histogram{Buckets: []Bucket{
+		{Count: 4, NDV: 4, LowerBound: []byte{12}, UpperBound: []byte{15}},
+	}}
+
+	got := estimateHistogramEqJoinRowCount(l, r)
+	// Only L's second bucket (10 rows, NDV 10) overlaps R's bucket (4 rows,
+	// NDV 4): 10*4/max(10,4) = 4.
+	want := 4.0
+	if got != want {
+		t.Fatalf("estimateHistogramEqJoinRowCount() = %v, want %v (a full cross product would also count the non-overlapping L[0] pair)", got, want)
+	}
+}
+
+func TestEstimateEqJoinRowCountPrefersTopNExactMatches(t *testing.T) {
+	l := &Column{
+		Histogram: Histogram{Buckets: []Bucket{{Count: 100, NDV: 50, LowerBound: []byte{0}, UpperBound: []byte{99}}}},
+		TopN:      &TopN{TopN: []TopNMeta{{Encoded: []byte("x"), Count: 5}}},
+	}
+	r := &Column{
+		Histogram: Histogram{Buckets: []Bucket{{Count: 100, NDV: 50, LowerBound: []byte{0}, UpperBound: []byte{99}}}},
+		TopN:      &TopN{TopN: []TopNMeta{{Encoded: []byte("x"), Count: 3}}},
+	}
+
+	got := EstimateEqJoinRowCount(l, r)
+	// TopN contributes 5*3=15 exactly; the histogram term for the single
+	// fully-overlapping bucket pair is 100*100/50 = 200.
+	want := 15.0 + 200.0
+	if got != want {
+		t.Fatalf("EstimateEqJoinRowCount() = %v, want %v", got, want)
+	}
+}
+
+func TestEstimateEqJoinRowCountFallsBackWithoutHistograms(t *testing.T) {
+	l := &Column{Histogram: Histogram{NDV: 10}}
+	r := &Column{Histogram: Histogram{NDV: 20}}
+	if got := EstimateEqJoinRowCount(l, r); got != 0 {
+		t.Fatalf("EstimateEqJoinRowCount() with no buckets/TopN and no rows = %v, want 0", got)
+	}
+}